@@ -16,18 +16,32 @@
 //
 // Usage:
 //
-// $ gentypes <path to debugProtocol.json>
+// $ gentypes [--schema-version=X.YY] <path to debugProtocol.json> [path to write dap/server Handler to]
+// $ gentypes diff <old debugProtocol.json> <new debugProtocol.json>
+//
+// The dap package source is always printed to stdout. If a second positional
+// argument is given, the dap/server Handler interface and request dispatcher
+// are also generated and written to that path. --schema-version tags the
+// generated file to a specific protocol revision; see buildTagHeader.
+//
+// The "diff" subcommand instead reports which top-level types and fields
+// were added, removed or changed between two schema revisions, for
+// maintainers regenerating against a new upstream debugProtocol.json.
 package main
 
 import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
 	"go/format"
 	"io/ioutil"
 	"log"
 	"os"
+	"reflect"
+	"regexp"
+	"sort"
 	"strings"
 )
 
@@ -54,13 +68,18 @@ func goFieldName(jsonPropName string) string {
 // parsePropertyType takes the JSON value of a property field and extracts
 // the Go type of the property. For example, given this map:
 //
-//  {
-//    "type": "string",
-//    "description": "The command to execute."
-//  },
+//	{
+//	  "type": "string",
+//	  "description": "The command to execute."
+//	},
 //
 // It will emit "string".
-func parsePropertyType(propValue map[string]interface{}) string {
+//
+// owner and propName identify the struct and field the property belongs to
+// and are used to name any helper type parsePropertyType has to emit for it
+// (an enum or a union); such helper types are appended to *extraTypes so the
+// caller can emit them alongside the struct that references them.
+func parsePropertyType(owner, propName string, propValue map[string]interface{}, extraTypes *[]string) string {
 	if ref, ok := propValue["$ref"]; ok {
 		return parseRef(ref)
 	}
@@ -74,6 +93,9 @@ func parsePropertyType(propValue map[string]interface{}) string {
 	case string:
 		switch propType {
 		case "string":
+			if enumType := maybeEmitEnumType(owner, propName, propValue, extraTypes); enumType != "" {
+				return enumType
+			}
 			return "string"
 		case "integer":
 			return "int"
@@ -85,7 +107,7 @@ func parsePropertyType(propValue map[string]interface{}) string {
 				log.Fatal("missing items type for property of array type:", propValue)
 			}
 			propItemsMap := propItems.(map[string]interface{})
-			return "[]" + parsePropertyType(propItemsMap)
+			return "[]" + parsePropertyType(owner, propName, propItemsMap, extraTypes)
 		case "object":
 			// When the type of a property is "object", we'll emit a map with a string
 			// key and a value type that depends on the type of the
@@ -94,14 +116,22 @@ func parsePropertyType(propValue map[string]interface{}) string {
 			if !ok {
 				log.Fatal("missing additionalProperties field when type=object:", propValue)
 			}
-			valueType := parsePropertyType(additionalProps.(map[string]interface{}))
+			valueType := parsePropertyType(owner, propName, additionalProps.(map[string]interface{}), extraTypes)
 			return fmt.Sprintf("map[string]%v", valueType)
 		default:
 			log.Fatal("unknown property type value", propType)
 		}
 
 	case []interface{}:
-		return "interface{}"
+		// A property whose schema "type" is itself a list (such as
+		// ["string", "number"]) is a oneof-like union: the value can be any one
+		// of the listed JSON types. When every alternative is a scalar, emit a
+		// small struct with one optional field per alternative, plus custom
+		// (Un)marshaling, instead of collapsing it to interface{} and losing
+		// the type information; otherwise (an "array"/"object"/"null"
+		// alternative, such as TerminatedEvent.body.restart) there's no Go
+		// scalar to hold it, so emitUnionType falls back to interface{}.
+		return emitUnionType(owner, propName, propType.([]interface{}), extraTypes)
 
 	default:
 		log.Fatal("unknown property type", propType)
@@ -110,6 +140,234 @@ func parsePropertyType(propValue map[string]interface{}) string {
 	panic("unreachable")
 }
 
+// jsonTypeToGoType maps a JSON schema primitive type name to the
+// corresponding Go type, for use by emitUnionType's alternatives. It reports
+// ok=false for a JSON type ("array", "object", "null") that has no scalar Go
+// representation, so the caller can fall back instead of emitting a field
+// with no sensible type.
+func jsonTypeToGoType(jsonType string) (goType, fieldName string, ok bool) {
+	switch jsonType {
+	case "string":
+		return "string", "String", true
+	case "number":
+		return "float64", "Number", true
+	case "integer":
+		return "int", "Integer", true
+	case "boolean":
+		return "bool", "Boolean", true
+	default:
+		return "", "", false
+	}
+}
+
+// goConstName builds an exported Go identifier for one value of an enum, by
+// title-casing its non-alphanumeric-separated words and prefixing it with
+// the enum's own type name to keep it unique across the package.
+func goConstName(enumTypeName, value string) string {
+	clean := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return ' '
+		}
+	}, value)
+	return enumTypeName + goFieldName(strings.ReplaceAll(clean, " ", "_"))
+}
+
+// enumValuesFrom extracts the string values of propValue's "enum" or
+// "_enum" list, or returns ok=false if neither key is present or the list
+// is empty. closed reports which key it came from: "enum" is a closed set
+// the DAP schema rejects any other value for, while "_enum" is advisory —
+// the schema explicitly documents it as a suggested, non-exhaustive list of
+// values, so an unlisted one isn't an error.
+func enumValuesFrom(propValue map[string]interface{}) (values []string, closed, ok bool) {
+	enumValue, hasEnum := propValue["enum"]
+	if !hasEnum {
+		enumValue, ok = propValue["_enum"]
+		if !ok {
+			return nil, false, false
+		}
+	} else {
+		closed = true
+	}
+	rawValues, ok := enumValue.([]interface{})
+	if !ok || len(rawValues) == 0 {
+		return nil, false, false
+	}
+	for _, v := range rawValues {
+		s, ok := v.(string)
+		if !ok {
+			log.Fatal("non-string enum value", v)
+		}
+		values = append(values, s)
+	}
+	return values, closed, true
+}
+
+// emitEnumBody writes, onto b, the constants and the UnmarshalJSON and
+// Validate methods shared by every enum type gentypes generates (whether
+// from a property's inline "enum"/"_enum", see maybeEmitEnumType, or a
+// top-level schema definition of type "string" with its own enum list), and
+// records typeName in enumTypeNames. If closed, an unlisted value is
+// rejected by both methods; otherwise (an advisory "_enum") values is
+// emitted only as named constants for convenience and any string is valid.
+func emitEnumBody(b *strings.Builder, typeName string, values []string, closed bool) {
+	enumTypeNames[typeName] = true
+
+	b.WriteString("const (\n")
+	var constNames []string
+	for _, v := range values {
+		constName := goConstName(typeName, v)
+		constNames = append(constNames, constName)
+		fmt.Fprintf(b, "\t%s %s = %q\n", constName, typeName, v)
+	}
+	b.WriteString(")\n\n")
+
+	if !closed {
+		fmt.Fprintf(b, "// UnmarshalJSON decodes data into e. The constants above are the DAP schema's\n")
+		fmt.Fprintf(b, "// suggested values for %s, not a closed set, so this never rejects a value\n", typeName)
+		fmt.Fprintf(b, "// outside them.\n")
+		fmt.Fprintf(b, "func (e *%s) UnmarshalJSON(data []byte) error {\n", typeName)
+		b.WriteString("\tvar s string\n")
+		b.WriteString("\tif err := json.Unmarshal(data, &s); err != nil {\n\t\treturn err\n\t}\n")
+		fmt.Fprintf(b, "\t*e = %s(s)\n\treturn nil\n}\n\n", typeName)
+
+		fmt.Fprintf(b, "// Validate always returns nil: %s is an open set per the DAP schema, so any\n", typeName)
+		b.WriteString("// string is valid.\n")
+		fmt.Fprintf(b, "func (e %s) Validate() error { return nil }\n", typeName)
+		return
+	}
+
+	fmt.Fprintf(b, "// UnmarshalJSON returns an error if data is not one of the allowed values for %s.\n", typeName)
+	fmt.Fprintf(b, "func (e *%s) UnmarshalJSON(data []byte) error {\n", typeName)
+	b.WriteString("\tvar s string\n")
+	b.WriteString("\tif err := json.Unmarshal(data, &s); err != nil {\n\t\treturn err\n\t}\n")
+	fmt.Fprintf(b, "\tswitch %s(s) {\n\tcase %s:\n", typeName, strings.Join(constNames, ", "))
+	fmt.Fprintf(b, "\t\t*e = %s(s)\n\t\treturn nil\n", typeName)
+	b.WriteString("\tdefault:\n")
+	fmt.Fprintf(b, "\t\treturn fmt.Errorf(\"invalid value %%q for %s\", s)\n", typeName)
+	b.WriteString("\t}\n}\n\n")
+
+	fmt.Fprintf(b, "// Validate returns an error if e is not one of the allowed values for %s.\n", typeName)
+	fmt.Fprintf(b, "func (e %s) Validate() error {\n", typeName)
+	fmt.Fprintf(b, "\tswitch e {\n\tcase %s:\n\t\treturn nil\n", strings.Join(constNames, ", "))
+	b.WriteString("\tdefault:\n")
+	fmt.Fprintf(b, "\t\treturn fmt.Errorf(\"invalid value %%q for %s\", string(e))\n", typeName)
+	b.WriteString("\t}\n}\n")
+}
+
+// maybeEmitEnumType checks propValue for a DAP schema "enum" or "_enum" list
+// (respectively a closed or an advisory set of string values). If present,
+// it appends a named string type, one constant per listed value, and the
+// UnmarshalJSON and Validate methods emitEnumBody picks for its closedness,
+// to extraTypes, and returns the new type's name; otherwise it returns "".
+func maybeEmitEnumType(owner, propName string, propValue map[string]interface{}, extraTypes *[]string) string {
+	values, closed, ok := enumValuesFrom(propValue)
+	if !ok {
+		return ""
+	}
+
+	typeName := replaceGoTypename(owner + goFieldName(propName))
+
+	var b strings.Builder
+	if closed {
+		fmt.Fprintf(&b, "// %s is the closed set of values allowed for %s.%s by the DAP schema.\n", typeName, owner, goFieldName(propName))
+	} else {
+		fmt.Fprintf(&b, "// %s lists the DAP schema's suggested values for %s.%s; it is an open\n", typeName, owner, goFieldName(propName))
+		fmt.Fprintf(&b, "// set, so other values are also valid.\n")
+	}
+	fmt.Fprintf(&b, "type %s string\n\n", typeName)
+	emitEnumBody(&b, typeName, values, closed)
+
+	*extraTypes = append(*extraTypes, b.String())
+	return typeName
+}
+
+// emitUnionType appends a struct type to extraTypes with one optional
+// pointer field per alternative in jsonTypes (such as ["string", "number"]),
+// plus custom Marshal/UnmarshalJSON methods that use whichever single field
+// is set, and returns the new type's name. If any alternative isn't a
+// scalar JSON type backed by a Go type (an "array", "object" or "null"
+// alternative, such as TerminatedEvent.body.restart or Source.adapterData),
+// there's no sensible struct to build, so it falls back to "interface{}".
+func emitUnionType(owner, propName string, jsonTypes []interface{}, extraTypes *[]string) string {
+	var goTypes, fieldNames []string
+	for _, jt := range jsonTypes {
+		jsonType, ok := jt.(string)
+		if !ok {
+			log.Fatal("non-string type alternative", jt)
+		}
+		goType, fieldName, ok := jsonTypeToGoType(jsonType)
+		if !ok {
+			return "interface{}"
+		}
+		goTypes = append(goTypes, goType)
+		fieldNames = append(fieldNames, fieldName)
+	}
+
+	typeName := replaceGoTypename(owner + goFieldName(propName) + "Union")
+	unionTypeNames[typeName] = true
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "// %s holds exactly one of its fields, mirroring a DAP schema property whose\n", typeName)
+	fmt.Fprintf(&b, "// \"type\" lists more than one alternative.\n")
+	fmt.Fprintf(&b, "type %s struct {\n", typeName)
+	for i, fieldName := range fieldNames {
+		fmt.Fprintf(&b, "\t%s *%s\n", fieldName, goTypes[i])
+	}
+	b.WriteString("}\n\n")
+
+	fmt.Fprintf(&b, "// MarshalJSON encodes whichever field of u is set.\n")
+	fmt.Fprintf(&b, "func (u %s) MarshalJSON() ([]byte, error) {\n", typeName)
+	for _, fieldName := range fieldNames {
+		fmt.Fprintf(&b, "\tif u.%s != nil {\n\t\treturn json.Marshal(u.%s)\n\t}\n", fieldName, fieldName)
+	}
+	b.WriteString("\treturn []byte(\"null\"), nil\n}\n\n")
+
+	fmt.Fprintf(&b, "// UnmarshalJSON tries each alternative of %s in turn and keeps the first one\n", typeName)
+	b.WriteString("// that successfully decodes data.\n")
+	fmt.Fprintf(&b, "func (u *%s) UnmarshalJSON(data []byte) error {\n", typeName)
+	for _, fieldName := range fieldNames {
+		localVar := "v" + fieldName
+		fmt.Fprintf(&b, "\tvar %s %s\n", localVar, strings.TrimPrefix(fieldTypeFor(typeName, fieldName), "*"))
+		fmt.Fprintf(&b, "\tif err := json.Unmarshal(data, &%s); err == nil {\n\t\tu.%s = &%s\n\t\treturn nil\n\t}\n", localVar, fieldName, localVar)
+	}
+	fmt.Fprintf(&b, "\treturn fmt.Errorf(\"%s: no alternative matched %%s\", data)\n}\n\n", typeName)
+
+	fmt.Fprintf(&b, "// Validate returns an error unless exactly one field of u is set, matching\n")
+	fmt.Fprintf(&b, "// the \"exactly one alternative\" semantics MarshalJSON and UnmarshalJSON rely on.\n")
+	fmt.Fprintf(&b, "func (u %s) Validate() error {\n", typeName)
+	b.WriteString("\tset := 0\n")
+	for _, fieldName := range fieldNames {
+		fmt.Fprintf(&b, "\tif u.%s != nil {\n\t\tset++\n\t}\n", fieldName)
+	}
+	fmt.Fprintf(&b, "\tif set != 1 {\n\t\treturn fmt.Errorf(\"%s: want exactly one alternative set, got %%d\", set)\n\t}\n", typeName)
+	b.WriteString("\treturn nil\n}\n")
+
+	*extraTypes = append(*extraTypes, b.String())
+	return typeName
+}
+
+// fieldTypeFor returns the declared Go type (with its leading "*") of one of
+// the pointer fields emitUnionType generates, by name, so UnmarshalJSON can
+// declare a correctly-typed local variable for each alternative.
+func fieldTypeFor(unionTypeName, fieldName string) string {
+	switch fieldName {
+	case "String":
+		return "*string"
+	case "Number":
+		return "*float64"
+	case "Integer":
+		return "*int"
+	case "Boolean":
+		return "*bool"
+	default:
+		log.Fatal("unknown union field name", fieldName)
+	}
+	panic("unreachable")
+}
+
 // maybeParseInheritance helps parse types that inherit from other types.
 // A type description can have an "allOf" key, which means it inherits from
 // another type description. Returns the name of the base type specified in
@@ -117,8 +375,8 @@ func parsePropertyType(propValue map[string]interface{}) string {
 //
 // Example:
 //
-//    "allOf": [ { "$ref": "#/definitions/ProtocolMessage" },
-//               {... type description ...} ]
+//	"allOf": [ { "$ref": "#/definitions/ProtocolMessage" },
+//	           {... type description ...} ]
 //
 // Returns base type ProtocolMessage and a map representing type description.
 // If there is no "allOf", returns an empty baseTypeName and descMap itself.
@@ -147,6 +405,221 @@ func maybeParseInheritance(descMap map[string]json.RawMessage) (baseTypeName str
 	return parseRef(baseTypeRef["$ref"]), typeDescJson
 }
 
+// commentWidth is the column gentypes wraps generated doc comments at, to
+// match the style of the hand-written comments elsewhere in the package.
+const commentWidth = 77 // 80 minus the "// " prefix
+
+// wrapComment splits text into lines of at most width bytes, breaking only
+// between words (a single word longer than width gets a line of its own),
+// and preserving text's own paragraph breaks (DAP descriptions are
+// frequently several sentences joined by "\n").
+func wrapComment(text string, width int) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		words := strings.Fields(paragraph)
+		if len(words) == 0 {
+			lines = append(lines, "")
+			continue
+		}
+		line := words[0]
+		for _, word := range words[1:] {
+			if len(line)+1+len(word) > width {
+				lines = append(lines, line)
+				line = word
+			} else {
+				line += " " + word
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// deprecatedReason reports whether a schema's "deprecated" key (a bare bool
+// or a string giving the reason) marks its entry deprecated, and if so
+// returns the reason to print, which may be empty.
+func deprecatedReason(deprecated interface{}) (reason string, ok bool) {
+	switch v := deprecated.(type) {
+	case string:
+		return v, true
+	case bool:
+		return "", v
+	default:
+		return "", false
+	}
+}
+
+// writeDocComment writes description, and then a "// Deprecated: ..." line
+// per https://go.dev/wiki/Deprecated if deprecated marks the entry
+// deprecated, as a Go doc comment indented by indent, wrapped at
+// commentWidth. It writes nothing if description is empty and the entry
+// isn't deprecated.
+func writeDocComment(b *strings.Builder, indent, description string, deprecated interface{}) {
+	if description != "" {
+		for _, line := range wrapComment(description, commentWidth) {
+			if line == "" {
+				fmt.Fprintf(b, "%s//\n", indent)
+			} else {
+				fmt.Fprintf(b, "%s// %s\n", indent, line)
+			}
+		}
+	}
+	if reason, isDeprecated := deprecatedReason(deprecated); isDeprecated {
+		if description != "" {
+			fmt.Fprintf(b, "%s//\n", indent)
+		}
+		lines := wrapComment(reason, commentWidth)
+		if len(lines) == 0 {
+			lines = []string{""}
+		}
+		for i, line := range lines {
+			if i == 0 {
+				fmt.Fprintf(b, "%s// Deprecated: %s\n", indent, line)
+			} else {
+				fmt.Fprintf(b, "%s// %s\n", indent, line)
+			}
+		}
+	}
+}
+
+// classifyGoType strips any pointer, slice or map wrapper off goType (as
+// emitted for a struct field) and reports which one, if any, it found, so
+// the Validate generator knows whether to guard a check behind a nil check,
+// iterate a slice or map's values, or check the bare value directly.
+func classifyGoType(goType string) (base, container string) {
+	switch {
+	case strings.HasPrefix(goType, "*"):
+		return strings.TrimPrefix(goType, "*"), "pointer"
+	case strings.HasPrefix(goType, "[]"):
+		return strings.TrimPrefix(goType, "[]"), "slice"
+	case strings.HasPrefix(goType, "map[string]"):
+		return strings.TrimPrefix(goType, "map[string]"), "map"
+	default:
+		return goType, ""
+	}
+}
+
+// isPrimitiveGoType reports whether goType is one of the bare types gentypes
+// emits without a Validate method of its own, as opposed to a generated
+// struct, enum or union type, which always gets one. interface{} is included
+// here: it's gentypes's fallback for a union alternative with no scalar Go
+// representation (see emitUnionType), so it has no Validate method either.
+func isPrimitiveGoType(goType string) bool {
+	switch goType {
+	case "string", "int", "bool", "float64", "interface{}":
+		return true
+	}
+	return false
+}
+
+// fieldValidateStmt returns the Go statement(s) Validate should run against
+// a single, already-dereferenced value: numeric/length bounds taken from
+// the schema's minimum/maximum/minLength/maxLength, followed by a
+// recursive call into the value's own Validate method if base isn't a bare
+// scalar. valueExpr is the Go expression the bounds are checked against
+// (such as "*r.Foo" or "v"), callExpr the one Validate is called on (such as
+// "r.Foo"); label names the field in the resulting error. Returns "" if there is
+// nothing to check.
+func fieldValidateStmt(valueExpr, callExpr, label, base string, propDesc map[string]interface{}) string {
+	var b strings.Builder
+	if min, ok := propDesc["minimum"].(float64); ok {
+		fmt.Fprintf(&b, "if %s < %v {\n\treturn fmt.Errorf(%q, %s)\n}\n", valueExpr, min, fmt.Sprintf("%s: must be >= %v, got %%v", label, min), valueExpr)
+	}
+	if max, ok := propDesc["maximum"].(float64); ok {
+		fmt.Fprintf(&b, "if %s > %v {\n\treturn fmt.Errorf(%q, %s)\n}\n", valueExpr, max, fmt.Sprintf("%s: must be <= %v, got %%v", label, max), valueExpr)
+	}
+	if minLen, ok := propDesc["minLength"].(float64); ok {
+		fmt.Fprintf(&b, "if len(%s) < %d {\n\treturn fmt.Errorf(%q, len(%s))\n}\n", valueExpr, int(minLen), fmt.Sprintf("%s: length must be >= %d, got %%d", label, int(minLen)), valueExpr)
+	}
+	if maxLen, ok := propDesc["maxLength"].(float64); ok {
+		fmt.Fprintf(&b, "if len(%s) > %d {\n\treturn fmt.Errorf(%q, len(%s))\n}\n", valueExpr, int(maxLen), fmt.Sprintf("%s: length must be <= %d, got %%d", label, int(maxLen)), valueExpr)
+	}
+	if !isPrimitiveGoType(base) {
+		fmt.Fprintf(&b, "if err := %s.Validate(); err != nil {\n\treturn fmt.Errorf(%q, err)\n}\n", callExpr, label+": %w")
+	}
+	return b.String()
+}
+
+// appendFieldValidation appends to *stmts the statement Validate should run
+// to check one struct field, given its Go field name, the Go type gentypes
+// emitted for it (including any pointer wrapping applied for an optional
+// scalar) and whether the schema marks it required. A pointer field is only
+// checked when set, since it represents an optional value, and is
+// dereferenced for bounds checks (but not for the nested Validate call,
+// which works the same on the pointer); a slice or map field has its
+// elements (respectively its values) checked (if they aren't themselves a
+// bare scalar), plus a presence (non-nil) check if required, since
+// encoding/json leaves an absent array/object field nil but an empty one
+// non-nil; a required bare string is checked non-empty, since unlike a
+// slice or map it has no nil to distinguish "absent" from "present" (a
+// required bool/int/float64 isn't checked for presence at all: zero is
+// itself a valid value for them, so there's no sentinel that wouldn't
+// reject legitimate payloads); anything else (a required struct, enum or
+// union) relies on its own nested Validate call to catch a missing value.
+func appendFieldValidation(stmts *[]string, recv, fieldName, goType string, required bool, propDesc map[string]interface{}) {
+	base, container := classifyGoType(goType)
+	expr := recv + "." + fieldName
+
+	switch container {
+	case "pointer":
+		inner := fieldValidateStmt("*"+expr, expr, fieldName, base, propDesc)
+		if inner == "" {
+			return
+		}
+		*stmts = append(*stmts, fmt.Sprintf("if %s != nil {\n%s}\n", expr, inner))
+	case "slice":
+		var b strings.Builder
+		if required {
+			fmt.Fprintf(&b, "if %s == nil {\n\treturn fmt.Errorf(%q)\n}\n", expr, fieldName+": required field is missing")
+		}
+		if !isPrimitiveGoType(base) {
+			fmt.Fprintf(&b, "for i, v := range %s {\n\tif err := v.Validate(); err != nil {\n\t\treturn fmt.Errorf(%q, i, err)\n\t}\n}\n",
+				expr, fieldName+"[%d]: %w")
+		}
+		if b.Len() > 0 {
+			*stmts = append(*stmts, b.String())
+		}
+	case "map":
+		var b strings.Builder
+		if required {
+			fmt.Fprintf(&b, "if %s == nil {\n\treturn fmt.Errorf(%q)\n}\n", expr, fieldName+": required field is missing")
+		}
+		if !isPrimitiveGoType(base) {
+			fmt.Fprintf(&b, "for k, v := range %s {\n\tif err := v.Validate(); err != nil {\n\t\treturn fmt.Errorf(%q, k, err)\n\t}\n}\n",
+				expr, fieldName+"[%s]: %w")
+		}
+		if b.Len() > 0 {
+			*stmts = append(*stmts, b.String())
+		}
+	default:
+		var b strings.Builder
+		if base == "string" {
+			fmt.Fprintf(&b, "if %s == \"\" {\n\treturn fmt.Errorf(%q)\n}\n", expr, fieldName+": required field is missing")
+		}
+		b.WriteString(fieldValidateStmt(expr, expr, fieldName, base, propDesc))
+		if b.Len() > 0 {
+			*stmts = append(*stmts, b.String())
+		}
+	}
+}
+
+// rawMapToAny re-decodes a map[string]json.RawMessage (used to preserve
+// property order elsewhere in this file) into a plain map[string]interface{},
+// for the few call sites, such as a top-level type's own "enum" list, that
+// don't care about order and can reuse the map[string]interface{}-based
+// helpers written for property descriptions.
+func rawMapToAny(m map[string]json.RawMessage) map[string]interface{} {
+	result := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		var decoded interface{}
+		if err := json.Unmarshal(v, &decoded); err != nil {
+			log.Fatal(err)
+		}
+		result[k] = decoded
+	}
+	return result
+}
+
 // emitToplevelType emits a single type into a string. It takes the type name
 // and a serialized json object representing the type. The json representation
 // will have fields: "type", "properties" etc.
@@ -173,8 +646,29 @@ func emitToplevelType(typeName string, descJson json.RawMessage) string {
 		log.Fatal(err)
 	}
 
+	var typeDescription string
+	if rawDesc, ok := descMap["description"]; ok {
+		if err := json.Unmarshal(rawDesc, &typeDescription); err != nil {
+			log.Fatal(err)
+		}
+	}
+	var typeDeprecated interface{}
+	if rawDeprecated, ok := descMap["deprecated"]; ok {
+		if err := json.Unmarshal(rawDeprecated, &typeDeprecated); err != nil {
+			log.Fatal(err)
+		}
+	}
+	writeDocComment(&b, "", typeDescription, typeDeprecated)
+
 	if descTypeString == "string" {
-		fmt.Fprintf(&b, "type %s string\n", typeName)
+		fmt.Fprintf(&b, "type %s string\n\n", typeName)
+		if values, closed, ok := enumValuesFrom(rawMapToAny(descMap)); ok {
+			emitEnumBody(&b, typeName, values, closed)
+		} else {
+			fmt.Fprintf(&b, "// Validate always returns nil: %s carries no schema constraints of its\n", typeName)
+			fmt.Fprintf(&b, "// own beyond being a string.\n")
+			fmt.Fprintf(&b, "func (%s) Validate() error { return nil }\n", typeName)
+		}
 		return b.String()
 	} else if descTypeString == "object" {
 		fmt.Fprintf(&b, "type %s struct {\n", typeName)
@@ -185,6 +679,15 @@ func emitToplevelType(typeName string, descJson json.RawMessage) string {
 		log.Fatal("want description type to be object or string, got ", descTypeString)
 	}
 
+	// Statements the generated Validate method below runs, one or more per
+	// field that carries a schema constraint or is itself a generated type
+	// with its own Validate method to call into.
+	recv := strings.ToLower(typeName[:1])
+	var validateStmts []string
+	if len(baseType) > 0 {
+		validateStmts = append(validateStmts, fmt.Sprintf("if err := %s.%s.Validate(); err != nil {\n\treturn err\n}\n", recv, baseType))
+	}
+
 	var propsMapOfJson map[string]json.RawMessage
 	if propsJson, ok := descMap["properties"]; ok {
 		if err := json.Unmarshal(propsJson, &propsMapOfJson); err != nil {
@@ -192,6 +695,7 @@ func emitToplevelType(typeName string, descJson json.RawMessage) string {
 		}
 	} else {
 		b.WriteString("}\n")
+		emitValidateMethod(&b, typeName, recv, validateStmts)
 		return b.String()
 	}
 
@@ -219,6 +723,10 @@ func emitToplevelType(typeName string, descJson json.RawMessage) string {
 	// done.
 	bodyType := ""
 
+	// Enum and union helper types that properties of this type need; emitted
+	// after the struct itself, for the same reason as bodyType above.
+	var extraTypes []string
+
 	for _, propName := range propsNamesInOrder {
 		// The JSON schema is designed for the TypeScript type system, where a
 		// subclass can redefine a field in a superclass with a refined type (such
@@ -229,9 +737,19 @@ func emitToplevelType(typeName string, descJson json.RawMessage) string {
 			continue
 		}
 		if propName == "command" && typeName != "Request" && typeName != "Response" {
+			var propDesc map[string]interface{}
+			if err := json.Unmarshal(propsMapOfJson[propName], &propDesc); err != nil {
+				log.Fatal(err)
+			}
+			recordMessageName(commandByType, typeName, propDesc)
 			continue
 		}
 		if propName == "event" && typeName != "Event" {
+			var propDesc map[string]interface{}
+			if err := json.Unmarshal(propsMapOfJson[propName], &propDesc); err != nil {
+				log.Fatal(err)
+			}
+			recordMessageName(eventByType, typeName, propDesc)
 			continue
 		}
 		if propName == "arguments" && typeName == "Request" {
@@ -256,36 +774,87 @@ func emitToplevelType(typeName string, descJson json.RawMessage) string {
 				bodyType = emitToplevelType(bodyTypeName, propsMapOfJson["body"])
 			}
 
+			bodyDesc, _ := propDesc["description"].(string)
+			writeDocComment(&b, "\t", bodyDesc, propDesc["deprecated"])
 			if requiredMap["body"] {
 				fmt.Fprintf(&b, "\t%s %s `json:\"body\"`\n", "Body", bodyTypeName)
 			} else {
 				fmt.Fprintf(&b, "\t%s %s `json:\"body,omitempty\"`\n", "Body", bodyTypeName)
 			}
+			appendFieldValidation(&validateStmts, recv, "Body", bodyTypeName, requiredMap["body"], propDesc)
 		} else {
 			// Go type of this property.
-			goType := parsePropertyType(propDesc)
+			goType := parsePropertyType(typeName, propName, propDesc, &extraTypes)
+
+			propDescription, _ := propDesc["description"].(string)
+			writeDocComment(&b, "\t", propDescription, propDesc["deprecated"])
 
 			jsonTag := fmt.Sprintf("`json:\"%s", propName)
 			if requiredMap[propName] {
 				jsonTag += "\"`"
 			} else {
 				jsonTag += ",omitempty\"`"
+				// Use a pointer for optional scalar fields so that an explicitly
+				// supplied zero value (false, 0, "") can be told apart from the
+				// field being absent altogether; encoding/json's omitempty only
+				// looks at whether the pointer itself is nil.
+				if isScalarGoType(goType) {
+					goType = "*" + goType
+				}
 			}
 
 			fmt.Fprintf(&b, "\t%s %s %s\n", goFieldName(propName), goType, jsonTag)
+			appendFieldValidation(&validateStmts, recv, goFieldName(propName), goType, requiredMap[propName], propDesc)
 		}
 	}
 
 	b.WriteString("}\n")
+	emitValidateMethod(&b, typeName, recv, validateStmts)
 
 	if len(bodyType) > 0 {
 		b.WriteString("\n")
 		b.WriteString(bodyType)
 	}
 
+	for _, extraType := range extraTypes {
+		b.WriteString("\n")
+		b.WriteString(extraType)
+	}
+
 	return b.String()
 }
 
+// emitValidateMethod writes typeName's Validate method, running stmts in
+// order and returning nil if none of them returned an error. Every
+// generated struct type gets one, even with an empty stmts (so other
+// generated code can call .Validate() uniformly on any message or nested
+// type without a type switch).
+func emitValidateMethod(b *strings.Builder, typeName, recv string, stmts []string) {
+	fmt.Fprintf(b, "\n// Validate reports whether %s satisfies the constraints declared by its DAP\n", typeName)
+	b.WriteString("// schema: enum values and numeric/length bounds are honored, nested types are\n")
+	b.WriteString("// valid, and required string, array and map fields are present. A required\n")
+	b.WriteString("// field of any other type is not checked for presence: its zero value passes\n")
+	b.WriteString("// unless it fails one of the other checks.\n")
+	fmt.Fprintf(b, "func (%s *%s) Validate() error {\n", recv, typeName)
+	for _, stmt := range stmts {
+		b.WriteString(stmt)
+	}
+	b.WriteString("return nil\n}\n")
+}
+
+// isScalarGoType reports whether goType is a bare scalar (string, int, bool,
+// float64 or a generated enum type, itself backed by string) or a generated
+// union type, rather than a slice, map or other struct, and so is a candidate
+// for the pointer treatment that distinguishes a present zero value from an
+// absent one.
+func isScalarGoType(goType string) bool {
+	switch goType {
+	case "string", "int", "bool", "float64":
+		return true
+	}
+	return enumTypeNames[goType] || unionTypeNames[goType]
+}
+
 // keysInOrder returns the keys in json object in b, in their original order.
 // Based on https://github.com/golang/go/issues/27179#issuecomment-415559968
 func keysInOrder(b []byte) ([]string, error) {
@@ -313,6 +882,47 @@ func keysInOrder(b []byte) ([]string, error) {
 	}
 }
 
+// commandByType and eventByType map a generated *Request or *Event type name
+// to the literal command/event name declared by its schema's "command" or
+// "event" property (a single-value "enum"). They are populated while
+// emitting types and consumed in main to emit the registry init() that lets
+// the codec dispatch wire messages to concrete types. Response types don't
+// redeclare the enum (a response's command always matches its request's),
+// so their registry entries are resolved via the NameRequest -> NameResponse
+// naming convention instead.
+var commandByType = make(map[string]string)
+var eventByType = make(map[string]string)
+
+// enumTypeNames records the names of enum types emitEnumBody has generated
+// so far (inline property enums and top-level enum definitions alike), so isScalarGoType can tell them apart from $ref types to
+// other generated structs (both are bare capitalized Go identifiers).
+var enumTypeNames = make(map[string]bool)
+
+// unionTypeNames records the names of union types emitUnionType has
+// generated so far; like enum types, they carry their own presence
+// semantics (all nil vs. one alternative set) so they're also pointer-
+// wrapped when optional. See isScalarGoType.
+var unionTypeNames = make(map[string]bool)
+
+// recordMessageName extracts the literal value of a schema "enum" property
+// (such as a Request's "command" or an Event's "event" field) and stores it
+// in dest under typeName, for later use when emitting the registry init().
+func recordMessageName(dest map[string]string, typeName string, propValue map[string]interface{}) {
+	enumValue, ok := propValue["enum"]
+	if !ok {
+		return
+	}
+	enumSlice, ok := enumValue.([]interface{})
+	if !ok || len(enumSlice) == 0 {
+		return
+	}
+	name, ok := enumSlice[0].(string)
+	if !ok {
+		return
+	}
+	dest[typeName] = name
+}
+
 // replaceGoTypename replaces conflicting type names in the JSON schema with
 // proper Go type names.
 func replaceGoTypename(typeName string) string {
@@ -321,6 +931,15 @@ func replaceGoTypename(typeName string) string {
 	if typeName == "Message" {
 		return "ErrorMessage"
 	}
+	// RequestMessage, ResponseMessage and EventMessage are likewise reserved:
+	// they name the interfaces messageAccessors emits for every generated
+	// *Request/*Response/*Event type. A property that would otherwise
+	// generate an enum or union type under one of these names (such as
+	// Response.message) is suffixed with "Enum" to dodge the collision.
+	switch typeName {
+	case "RequestMessage", "ResponseMessage", "EventMessage":
+		return typeName + "Enum"
+	}
 	return typeName
 }
 
@@ -367,6 +986,11 @@ const preamble = `// Copyright 2019 Google LLC
 
 package dap
 
+import (
+	"encoding/json"
+	"fmt"
+)
+
 // Message is an interface that all DAP message types implement. It's not part
 // of the protocol but is used to enforce static typing in Go code.
 //
@@ -376,12 +1000,204 @@ type Message interface {
 	isMessage()
 }
 
+// Validator is implemented by every generated type: the message types
+// themselves and every nested, enum and union type they're built from.
+// DecodeProtocolMessage and WriteProtocolMessage call Validate after
+// unmarshaling a message and before marshaling one, so a payload that
+// violates its DAP schema (an unset field the schema requires, an
+// out-of-range enum or numeric value, and so on) is rejected before it
+// reaches a Handler or goes out over the wire.
+type Validator interface {
+	Validate() error
+}
+
 `
 
+// messageAccessors is emitted verbatim into the generated dap package, after
+// the base Request/Response/Event/ProtocolMessage types have been generated.
+// It is not schema-driven: these fields exist on every version of the base
+// protocol, so the accessors are the same regardless of debugProtocol.json.
+const messageAccessors = `
+// GetSeq returns the message's sequence number.
+func (p ProtocolMessage) GetSeq() int { return p.Seq }
+
+// SetSeq sets the message's sequence number.
+func (p *ProtocolMessage) SetSeq(seq int) { p.Seq = seq }
+
+// SetType sets the message's protocol-level discriminator ("request",
+// "response" or "event"). This assumes debugProtocol.json declares an
+// "enum"/"_enum" for ProtocolMessage's "type" property, as the upstream
+// specification does, so that it is generated as the named ProtocolMessageType
+// rather than a plain string.
+func (p *ProtocolMessage) SetType(t ProtocolMessageType) { p.Type = t }
+
+// GetCommand returns the request's command name.
+func (r Request) GetCommand() string { return r.Command }
+
+// GetEvent returns the event's name.
+func (e Event) GetEvent() string { return e.Event }
+
+// SetRequestSeq sets the sequence number of the request this response answers.
+func (r *Response) SetRequestSeq(seq int) { r.RequestSeq = seq }
+
+// SetSuccess sets whether the request this response answers succeeded.
+func (r *Response) SetSuccess(success bool) { r.Success = success }
+
+// SetCommand sets the command name this response answers.
+func (r *Response) SetCommand(command string) { r.Command = command }
+
+// RequestMessage is implemented by every generated *Request type, through
+// the embedded Request and ProtocolMessage fields.
+type RequestMessage interface {
+	Message
+	GetSeq() int
+	GetCommand() string
+}
+
+// ResponseMessage is implemented by every generated *Response type, through
+// the embedded Response and ProtocolMessage fields.
+type ResponseMessage interface {
+	Message
+	SetRequestSeq(int)
+	SetSuccess(bool)
+	SetCommand(string)
+	SetType(ProtocolMessageType)
+}
+
+// EventMessage is implemented by every generated *Event type, through the
+// embedded Event and ProtocolMessage fields.
+type EventMessage interface {
+	Message
+	GetEvent() string
+	SetType(ProtocolMessageType)
+}
+`
+
+// serverPreamble is the fixed header of the generated dap/server Handler
+// file: license, generation notice and imports.
+const serverPreamble = `// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// DO NOT EDIT: This file is auto-generated.
+// DAP spec: https://microsoft.github.io/debug-adapter-protocol/specification
+// See cmd/gentypes/README.md for additional details.
+
+package server
+
+import (
+	"fmt"
+
+	"github.com/tamayika/go-dap/dap"
+)
+
+`
+
+// exportedCommandName turns a DAP command name (such as "setBreakpoints")
+// into the corresponding exported Handler method name ("SetBreakpoints").
+func exportedCommandName(command string) string {
+	return strings.ToUpper(command[:1]) + command[1:]
+}
+
+// emitServerPackage emits the dap/server Handler interface (one method per
+// DAP request) and the dispatch function that calls the right Handler
+// method for a decoded request, keeping both in sync with
+// debugProtocol.json the same way a gRPC/protobuf generator emits service
+// stubs from a .proto file. It relies on commandByType, populated while
+// emitting the dap package in the same run.
+func emitServerPackage(typeNames []string) string {
+	var b strings.Builder
+	b.WriteString(serverPreamble)
+
+	type requestInfo struct {
+		command      string
+		methodName   string
+		requestType  string
+		responseType string
+	}
+	var requests []requestInfo
+	for _, typeName := range typeNames {
+		typeName = replaceGoTypename(typeName)
+		if !strings.HasSuffix(typeName, "Request") {
+			continue
+		}
+		command, ok := commandByType[typeName]
+		if !ok {
+			continue
+		}
+		requests = append(requests, requestInfo{
+			command:      command,
+			methodName:   exportedCommandName(command),
+			requestType:  typeName,
+			responseType: strings.TrimSuffix(typeName, "Request") + "Response",
+		})
+	}
+
+	b.WriteString("// Handler implements the behavior of a DAP debug adapter: one method per\n")
+	b.WriteString("// request defined by the DAP specification. gentypes regenerates this\n")
+	b.WriteString("// interface from debugProtocol.json, so adding support for a new request\n")
+	b.WriteString("// upstream surfaces here as a new method to implement.\n")
+	b.WriteString("type Handler interface {\n")
+	for _, r := range requests {
+		fmt.Fprintf(&b, "\t%s(*dap.%s) (*dap.%s, error)\n", r.methodName, r.requestType, r.responseType)
+	}
+	b.WriteString("}\n\n")
+
+	b.WriteString("// dispatch calls the Handler method for request and returns the *dap.Response\n")
+	b.WriteString("// it produced, or an error to report back to the client as an ErrorResponse.\n")
+	b.WriteString("func dispatch(h Handler, request dap.Message) (dap.Message, error) {\n")
+	b.WriteString("\tswitch request := request.(type) {\n")
+	for _, r := range requests {
+		fmt.Fprintf(&b, "\tcase *dap.%s:\n\t\treturn h.%s(request)\n", r.requestType, r.methodName)
+	}
+	b.WriteString("\tdefault:\n")
+	b.WriteString("\t\treturn nil, fmt.Errorf(\"server: no handler registered for request type %T\", request)\n")
+	b.WriteString("\t}\n}\n")
+
+	return b.String()
+}
+
 func main() {
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
 
-	inputFilename := os.Args[1]
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		runDiff(os.Args[2:])
+		return
+	}
+
+	runGenerate(os.Args[1:])
+}
+
+// runGenerate is the default invocation: it emits the dap package source to
+// stdout and, if a server output path was given, the dap/server Handler
+// interface and dispatcher alongside it.
+func runGenerate(args []string) {
+	fs := flag.NewFlagSet("gentypes", flag.ExitOnError)
+	schemaVersion := fs.String("schema-version", "", `DAP schema version the input was generated from (e.g. "1.55"). If set, `+
+		`the generated dap package is restricted to that version with the Go build tag dap_v1_55 (dots replaced by `+
+		`underscores) and carries a SchemaVersion constant, so a project generated from more than one revision can keep `+
+		`each in its own build-tagged file (such as messages_1_55.go, messages_1_64.go) and select between them with `+
+		`"go build -tags dap_v1_55".`)
+	if err := fs.Parse(args); err != nil {
+		log.Fatal(err)
+	}
+
+	positional := fs.Args()
+	if len(positional) < 1 {
+		log.Fatal("usage: gentypes [--schema-version=X.YY] <path to debugProtocol.json> [path to write dap/server Handler to]")
+	}
+
+	inputFilename := positional[0]
 	inputData, err := ioutil.ReadFile(inputFilename)
 	if err != nil {
 		log.Fatal(err)
@@ -398,6 +1214,10 @@ func main() {
 
 	var b strings.Builder
 	b.WriteString(preamble)
+	if *schemaVersion != "" {
+		fmt.Fprintf(&b, "// SchemaVersion is the DAP schema version this file was generated from.\n")
+		fmt.Fprintf(&b, "const SchemaVersion = %q\n\n", *schemaVersion)
+	}
 
 	typeNames, err := keysInOrder(m["definitions"])
 	if err != nil {
@@ -418,10 +1238,238 @@ func main() {
 		}
 	}
 
+	// Generic accessors promoted onto every generated *Request/*Response type
+	// through the embedded base types, so code like dap/server can read and
+	// stamp the bookkeeping fields (seq, command, success) without a
+	// per-message-type switch of its own.
+	b.WriteString(messageAccessors)
+
+	// Register every *Request, *Response and *Event type with the codec so it
+	// can dispatch wire messages to the concrete Go type by command/event
+	// name. See commandByType and eventByType above.
+	b.WriteString("\nfunc init() {\n")
+	for _, typeName := range typeNames {
+		typeName = replaceGoTypename(typeName)
+		switch {
+		case strings.HasSuffix(typeName, "Request"):
+			if command, ok := commandByType[typeName]; ok {
+				fmt.Fprintf(&b, "\tregisterRequestType(%q, func() Message { return new(%s) })\n", command, typeName)
+			}
+		case strings.HasSuffix(typeName, "Event"):
+			if event, ok := eventByType[typeName]; ok {
+				fmt.Fprintf(&b, "\tregisterEventType(%q, func() Message { return new(%s) })\n", event, typeName)
+			}
+		case strings.HasSuffix(typeName, "Response"):
+			requestName := strings.TrimSuffix(typeName, "Response") + "Request"
+			if command, ok := commandByType[requestName]; ok {
+				fmt.Fprintf(&b, "\tregisterResponseType(%q, func() Message { return new(%s) })\n", command, typeName)
+			}
+		}
+	}
+	b.WriteString("}\n")
+
 	wholeFile := []byte(b.String())
+	if *schemaVersion != "" {
+		wholeFile = append([]byte(buildTagHeader(*schemaVersion)), wholeFile...)
+	}
 	formatted, err := format.Source(wholeFile)
 	if err != nil {
 		log.Fatal(err)
 	}
 	fmt.Print(string(formatted))
-}
\ No newline at end of file
+
+	if len(positional) > 1 {
+		serverSource := []byte(emitServerPackage(typeNames))
+		serverFormatted, err := format.Source(serverSource)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := ioutil.WriteFile(positional[1], serverFormatted, 0644); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// validSchemaVersion matches the characters buildTagForVersion knows how to
+// turn into a valid Go build tag: digits, dots and hyphens, as in "1.55" or
+// "1.55.0-1".
+var validSchemaVersion = regexp.MustCompile(`^[0-9][0-9.-]*$`)
+
+// buildTagForVersion turns a DAP schema version such as "1.55" into the Go
+// build tag ("dap_v1_55") used to select it; build tags may not contain ".".
+// It fails fast on a version containing characters that would make the
+// result a malformed (or, worse, a differently-meaningful) build constraint.
+func buildTagForVersion(version string) string {
+	if !validSchemaVersion.MatchString(version) {
+		log.Fatalf("--schema-version %q: want digits, dots and hyphens only (e.g. \"1.55\")", version)
+	}
+	return "dap_v" + strings.NewReplacer(".", "_", "-", "_").Replace(version)
+}
+
+// buildTagHeader returns the Go build constraint comment (in both the
+// //go:build and the older // +build form, for toolchains predating Go
+// 1.17) restricting a generated file to builds that request this schema
+// version, so messages_1_55.go and messages_1_64.go can coexist in the same
+// dap package and "go build -tags dap_v1_55" selects one of them.
+func buildTagHeader(version string) string {
+	tag := buildTagForVersion(version)
+	return fmt.Sprintf("//go:build %s\n// +build %s\n\n", tag, tag)
+}
+
+// runDiff implements the "gentypes diff" subcommand: it loads the
+// "definitions" map of each of the two given debugProtocol.json files and
+// prints which top-level types were added, removed, or (for types present
+// in both) had fields added, removed, changed required-ness or otherwise
+// changed their schema, so a
+// maintainer regenerating against a new upstream schema gets a reviewable
+// summary instead of a giant unlabeled diff of the generated file.
+func runDiff(args []string) {
+	if len(args) != 2 {
+		log.Fatal("usage: gentypes diff <old debugProtocol.json> <new debugProtocol.json>")
+	}
+
+	oldDefs, oldNames := loadDefinitions(args[0])
+	newDefs, newNames := loadDefinitions(args[1])
+
+	oldSet := make(map[string]bool, len(oldNames))
+	for _, name := range oldNames {
+		oldSet[name] = true
+	}
+	newSet := make(map[string]bool, len(newNames))
+	for _, name := range newNames {
+		newSet[name] = true
+	}
+
+	for _, name := range newNames {
+		if !oldSet[name] {
+			fmt.Printf("+ %s\n", name)
+		}
+	}
+	for _, name := range oldNames {
+		if !newSet[name] {
+			fmt.Printf("- %s\n", name)
+		}
+	}
+	for _, name := range newNames {
+		if !oldSet[name] {
+			continue
+		}
+		changes := diffType(oldDefs[name], newDefs[name])
+		if len(changes) == 0 {
+			continue
+		}
+		fmt.Printf("~ %s\n", name)
+		for _, change := range changes {
+			fmt.Printf("    %s\n", change)
+		}
+	}
+}
+
+// loadDefinitions reads path as a debugProtocol.json-shaped schema and
+// returns its "definitions" map along with the type names in schema order.
+func loadDefinitions(path string) (map[string]json.RawMessage, []string) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(data, &m); err != nil {
+		log.Fatal(err)
+	}
+	var typeMap map[string]json.RawMessage
+	if err := json.Unmarshal(m["definitions"], &typeMap); err != nil {
+		log.Fatal(err)
+	}
+	names, err := keysInOrder(m["definitions"])
+	if err != nil {
+		log.Fatal(err)
+	}
+	return typeMap, names
+}
+
+// diffType compares two revisions of the same top-level type and returns
+// a description of each property that was added or removed, had its
+// "required"-ness flip, or had its schema otherwise changed (a property can
+// get both of the last two). It doesn't recurse into nested $ref types, since
+// those are reported as their own top-level changes when they differ.
+func diffType(oldDesc, newDesc json.RawMessage) []string {
+	oldProps, oldRequired := propertiesOf(oldDesc)
+	newProps, newRequired := propertiesOf(newDesc)
+
+	var changes []string
+	for name := range newProps {
+		if _, ok := oldProps[name]; !ok {
+			changes = append(changes, fmt.Sprintf("+ field %s", name))
+		}
+	}
+	for name := range oldProps {
+		if _, ok := newProps[name]; !ok {
+			changes = append(changes, fmt.Sprintf("- field %s", name))
+		}
+	}
+	for name := range newProps {
+		old, ok := oldProps[name]
+		if !ok {
+			continue
+		}
+		if oldRequired[name] != newRequired[name] {
+			if newRequired[name] {
+				changes = append(changes, fmt.Sprintf("  field %s became required", name))
+			} else {
+				changes = append(changes, fmt.Sprintf("  field %s became optional", name))
+			}
+		}
+		if !equalJSON(old, newProps[name]) {
+			changes = append(changes, fmt.Sprintf("  field %s changed", name))
+		}
+	}
+
+	sort.Strings(changes)
+	return changes
+}
+
+// propertiesOf returns a type description's own properties, keyed by name,
+// and which of them are listed as required; a type with no "properties" key
+// (such as a bare string enum) reports no properties.
+func propertiesOf(descJson json.RawMessage) (map[string]json.RawMessage, map[string]bool) {
+	var descMap map[string]json.RawMessage
+	if err := json.Unmarshal(descJson, &descMap); err != nil {
+		log.Fatal(err)
+	}
+	_, descMap = maybeParseInheritance(descMap)
+
+	props := make(map[string]json.RawMessage)
+	if propsJson, ok := descMap["properties"]; ok {
+		if err := json.Unmarshal(propsJson, &props); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	required := make(map[string]bool)
+	if requiredJson, ok := descMap["required"]; ok {
+		var names []string
+		if err := json.Unmarshal(requiredJson, &names); err != nil {
+			log.Fatal(err)
+		}
+		for _, name := range names {
+			required[name] = true
+		}
+	}
+
+	return props, required
+}
+
+// equalJSON reports whether a and b decode to the same value, ignoring
+// surface differences like key order or spacing; used by diffType to catch
+// a property whose schema changed in some way other than being added,
+// removed, or toggling required (such as its type, $ref, or enum list).
+func equalJSON(a, b json.RawMessage) bool {
+	var da, db interface{}
+	if err := json.Unmarshal(a, &da); err != nil {
+		log.Fatal(err)
+	}
+	if err := json.Unmarshal(b, &db); err != nil {
+		log.Fatal(err)
+	}
+	return reflect.DeepEqual(da, db)
+}
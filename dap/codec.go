@@ -0,0 +1,168 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dap
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+)
+
+// ReadProtocolMessage reads a single message from reader, which must be
+// framed per the DAP base protocol: a "Content-Length" header, a blank
+// line, and a JSON-encoded body. Headers are matched case-insensitively and
+// an optional "Content-Type" header is accepted and ignored, per the spec.
+//
+// The returned Message is the concrete generated type registered for the
+// body's "type" field (and its "command" field for requests and responses,
+// or its "event" field for events); see DecodeProtocolMessage.
+func ReadProtocolMessage(reader *bufio.Reader) (Message, error) {
+	tp := textproto.NewReader(reader)
+	mimeHeader, err := tp.ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	contentLengthStr := mimeHeader.Get("Content-Length")
+	if contentLengthStr == "" {
+		return nil, fmt.Errorf("dap: header is missing 'Content-Length'")
+	}
+	contentLength, err := strconv.Atoi(contentLengthStr)
+	if err != nil {
+		return nil, fmt.Errorf("dap: invalid 'Content-Length' %q: %v", contentLengthStr, err)
+	}
+
+	body := make([]byte, contentLength)
+	if _, err := io.ReadFull(reader, body); err != nil {
+		return nil, err
+	}
+
+	return DecodeProtocolMessage(body)
+}
+
+// WriteProtocolMessage writes message to w, framed per the DAP base
+// protocol: a "Content-Length" header, a blank line, and the JSON encoding
+// of message. If message implements Validator, it is validated before being
+// marshaled.
+func WriteProtocolMessage(w io.Writer, message Message) error {
+	if validator, ok := message.(Validator); ok {
+		if err := validator.Validate(); err != nil {
+			return fmt.Errorf("dap: %w", err)
+		}
+	}
+
+	content, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "Content-Length: %d\r\n\r\n", len(content))
+	buf.Write(content)
+
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// DecodeProtocolMessage parses content as the JSON body of a single DAP
+// message (without the surrounding header) and returns the concrete Message
+// type registered for it. It is split out from ReadProtocolMessage so tests
+// can decode a message body directly, without framing it first.
+func DecodeProtocolMessage(content []byte) (Message, error) {
+	var protocolMessage ProtocolMessage
+	if err := json.Unmarshal(content, &protocolMessage); err != nil {
+		return nil, err
+	}
+
+	switch protocolMessage.Type {
+	case "request":
+		var request struct {
+			Command string `json:"command"`
+		}
+		if err := json.Unmarshal(content, &request); err != nil {
+			return nil, err
+		}
+		return decodeMessage(requestRegistry, request.Command, content)
+	case "response":
+		var response struct {
+			Command string `json:"command"`
+		}
+		if err := json.Unmarshal(content, &response); err != nil {
+			return nil, err
+		}
+		return decodeMessage(responseRegistry, response.Command, content)
+	case "event":
+		var event struct {
+			Event string `json:"event"`
+		}
+		if err := json.Unmarshal(content, &event); err != nil {
+			return nil, err
+		}
+		return decodeMessage(eventRegistry, event.Event, content)
+	default:
+		return nil, fmt.Errorf("dap: unknown message type %q", protocolMessage.Type)
+	}
+}
+
+// decodeMessage looks up name (a command or event name) in registry,
+// instantiates the registered type, unmarshals content into it and, if the
+// type implements Validator, validates it.
+func decodeMessage(registry map[string]func() Message, name string, content []byte) (Message, error) {
+	newMessage, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("dap: no message type registered for %q", name)
+	}
+	message := newMessage()
+	if err := json.Unmarshal(content, message); err != nil {
+		return nil, err
+	}
+	if validator, ok := message.(Validator); ok {
+		if err := validator.Validate(); err != nil {
+			return nil, fmt.Errorf("dap: %w", err)
+		}
+	}
+	return message, nil
+}
+
+// requestRegistry, responseRegistry and eventRegistry map a DAP command or
+// event name to a constructor for the generated Go type that represents it.
+// They are populated by the init() function gentypes emits alongside the
+// generated message types, so that every *Request, *Response and *Event
+// type defined in debugProtocol.json is dispatchable without hand
+// maintenance of this package.
+var (
+	requestRegistry  = make(map[string]func() Message)
+	responseRegistry = make(map[string]func() Message)
+	eventRegistry    = make(map[string]func() Message)
+)
+
+// registerRequestType, registerResponseType and registerEventType are called
+// from generated code to populate the registries above. They are not
+// intended to be called directly.
+func registerRequestType(command string, newMessage func() Message) {
+	requestRegistry[command] = newMessage
+}
+
+func registerResponseType(command string, newMessage func() Message) {
+	responseRegistry[command] = newMessage
+}
+
+func registerEventType(event string, newMessage func() Message) {
+	eventRegistry[event] = newMessage
+}
@@ -0,0 +1,128 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package server provides scaffolding for implementing a DAP debug adapter:
+// a Handler interface with one method per DAP request (generated into this
+// package by cmd/gentypes) and a Session type that serves a single
+// client connection against an implementation of it.
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/tamayika/go-dap/dap"
+)
+
+// Session reads DAP requests from a client, dispatches each to h, and
+// writes the resulting response back to the client. It also lets h push
+// asynchronous events to the client via SendEvent. A Session serves a
+// single client connection; use one per connection.
+type Session struct {
+	reader  *bufio.Reader
+	handler Handler
+
+	writeMu sync.Mutex
+	writer  io.Writer
+
+	seqMu sync.Mutex
+	seq   int
+}
+
+// NewSession returns a Session that reads requests from r, dispatches them
+// to h, and writes responses and events to w.
+func NewSession(r io.Reader, w io.Writer, h Handler) *Session {
+	return &Session{
+		reader:  bufio.NewReader(r),
+		handler: h,
+		writer:  w,
+	}
+}
+
+// Serve reads and handles requests until it fails to read a message,
+// receives a message that isn't a request, or fails to write a response,
+// and returns that error; a client disconnecting cleanly surfaces as io.EOF.
+func (s *Session) Serve() error {
+	for {
+		if err := s.serveOne(); err != nil {
+			return err
+		}
+	}
+}
+
+// serveOne reads, dispatches and responds to a single request.
+func (s *Session) serveOne() error {
+	message, err := dap.ReadProtocolMessage(s.reader)
+	if err != nil {
+		return err
+	}
+
+	request, ok := message.(dap.RequestMessage)
+	if !ok {
+		return fmt.Errorf("server: expected a request, got %T", message)
+	}
+
+	response, handlerErr := dispatch(s.handler, message)
+	if handlerErr != nil {
+		response = newErrorResponse(handlerErr)
+	}
+
+	if responseMessage, ok := response.(dap.ResponseMessage); ok {
+		responseMessage.SetRequestSeq(request.GetSeq())
+		responseMessage.SetCommand(request.GetCommand())
+		responseMessage.SetSuccess(handlerErr == nil)
+		responseMessage.SetType(dap.ProtocolMessageTypeResponse)
+	}
+
+	return s.send(response)
+}
+
+// newErrorResponse wraps err in the DAP ErrorResponse that is sent back to
+// the client in place of the response a Handler method failed to produce.
+func newErrorResponse(err error) *dap.ErrorResponse {
+	return &dap.ErrorResponse{
+		Body: dap.ErrorResponseBody{
+			Error: dap.ErrorMessage{Format: err.Error()},
+		},
+	}
+}
+
+// SendEvent sends event to the client, assigning it the next sequence
+// number. It may be called concurrently with Serve to notify the client of
+// state changes the client didn't request, such as a breakpoint being hit.
+func (s *Session) SendEvent(event dap.Message) error {
+	if eventMessage, ok := event.(dap.EventMessage); ok {
+		eventMessage.SetType(dap.ProtocolMessageTypeEvent)
+	}
+	return s.send(event)
+}
+
+// send assigns message the next sequence number and writes it to the
+// client, synchronized against concurrent sends from Serve and SendEvent.
+func (s *Session) send(message dap.Message) error {
+	s.seqMu.Lock()
+	s.seq++
+	seq := s.seq
+	s.seqMu.Unlock()
+
+	if sequenced, ok := message.(interface{ SetSeq(int) }); ok {
+		sequenced.SetSeq(seq)
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return dap.WriteProtocolMessage(s.writer, message)
+}